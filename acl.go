@@ -0,0 +1,31 @@
+/*
+ * Minio Client (C) 2014, 2015 Minio, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+// bucketACL is one of the canned ACLs mc can set on a bucket.
+type bucketACL string
+
+// isValidBucketACL reports whether acl is one of the canned ACLs S3
+// understands.
+func (acl bucketACL) isValidBucketACL() bool {
+	switch acl {
+	case "private", "public-read", "public-read-write", "authenticated-read":
+		return true
+	default:
+		return false
+	}
+}
@@ -0,0 +1,66 @@
+/*
+ * Minio Client (C) 2014, 2015 Minio, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	"regexp"
+	"strings"
+)
+
+// aliasNameRegexp matches a valid alias: a letter followed by any number
+// of letters, digits or hyphens.
+var aliasNameRegexp = regexp.MustCompile("^[a-zA-Z][a-zA-Z0-9-]*$")
+
+// reservedAliasNames are command names an alias may not shadow.
+var reservedAliasNames = map[string]bool{
+	"help":    true,
+	"config":  true,
+	"update":  true,
+	"version": true,
+}
+
+// isValidAliasName reports whether name is safe to use as an alias: it
+// must match aliasNameRegexp and must not collide with a reserved
+// command name.
+func isValidAliasName(name string) bool {
+	return aliasNameRegexp.MatchString(name) && !reservedAliasNames[name]
+}
+
+// aliasExpand resolves a leading "alias:" prefix of rawURL against
+// aliases, e.g. "play:bucket" with aliases["play"] = "http://play..."
+// becomes "http://play.../bucket". A rawURL with no matching alias
+// prefix, or without any aliases configured, is returned unchanged.
+func aliasExpand(rawURL string, aliases map[string]string) (string, error) {
+	if aliases == nil {
+		return rawURL, nil
+	}
+	idx := strings.Index(rawURL, ":")
+	if idx < 0 {
+		return rawURL, nil
+	}
+	prefix, rest := rawURL[:idx], rawURL[idx+1:]
+	if strings.HasPrefix(rest, "//") {
+		// Looks like a real URL scheme (e.g. "minio://..."), not an
+		// alias reference.
+		return rawURL, nil
+	}
+	expansion, ok := aliases[prefix]
+	if !ok {
+		return rawURL, nil
+	}
+	return expansion + "/" + rest, nil
+}
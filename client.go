@@ -0,0 +1,189 @@
+/*
+ * Minio Client (C) 2014, 2015 Minio, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	"context"
+	"fmt"
+)
+
+// client is the minimal surface mc's commands drive an S3 compatible
+// endpoint through. s3Client, below, is its concrete implementation.
+type client interface {
+	URL() string
+	GetObject(bucket, object string) error
+	PutObject(bucket, object string) error
+	ListObjects(bucket string) error
+	RemoveObject(bucket, object string) error
+	StatObject(bucket, object string) error
+	PutBucketPolicy(bucket string) error
+}
+
+// hostConfig holds the long lived static credentials mc knows for a
+// given alias, as stored in configV1.Aliases' richer successor.
+type hostConfig struct {
+	AccessKeyID     string
+	SecretAccessKey string
+}
+
+// getNewClient builds a client for url, resolving credentials through
+// the provider chain in credentials.go when auth is empty. debug is
+// stored on the returned client and is not otherwise acted on. The chain
+// itself is kept alongside the resolved client so a 401/403 from the
+// server can invalidate it and force a fresh resolution.
+func getNewClient(url string, auth *hostConfig, debug bool) (client, error) {
+	return getNewClientForAlias("", url, auth, debug)
+}
+
+// getNewClientForAlias is getNewClient's alias-aware counterpart: it
+// applies globalPolicyConfig's per-alias override, if any, instead of
+// always falling back to the top-level policy config.
+func getNewClientForAlias(alias, url string, auth *hostConfig, debug bool) (client, error) {
+	chain := newCredentialsChain(auth)
+	value, err := chain.Retrieve()
+	if err != nil {
+		return nil, err
+	}
+	return &s3Client{
+		url:       url,
+		creds:     value,
+		credChain: chain,
+		debug:     debug,
+		policy:    newPolicyAuthorizer(globalPolicyConfig.forAlias(alias)),
+	}, nil
+}
+
+// globalPolicyConfig holds the `policy` section of the active
+// config.json. It is zero-valued (URL == "") until loadGlobalPolicyConfig
+// is called, which disables the authorization hook entirely.
+var globalPolicyConfig policyConfig
+
+// setGlobalPolicyConfig installs cfg as the policy configuration every
+// subsequently constructed client authorizes mutating calls against.
+func setGlobalPolicyConfig(cfg policyConfig) {
+	globalPolicyConfig = cfg
+}
+
+// loadGlobalPolicyConfig reads the `policy` section out of config.json at
+// whichever schema version is on disk and installs it via
+// setGlobalPolicyConfig, so the OPA hook is actually active for a real
+// run rather than only in tests that set globalPolicyConfig directly.
+func loadGlobalPolicyConfig() error {
+	configFile, err := getMcConfigPath()
+	if err != nil {
+		return err
+	}
+	version, err := configVersion(configFile)
+	if err != nil {
+		return err
+	}
+	qc, err := loadConfigAtVersion(configFile, version)
+	if err != nil {
+		return err
+	}
+	switch data := qc.Data().(type) {
+	case *configV1:
+		setGlobalPolicyConfig(data.Policy)
+	case *configV2:
+		setGlobalPolicyConfig(data.Policy)
+	default:
+		return fmt.Errorf("policy: no policy config for version %q", version)
+	}
+	return nil
+}
+
+// s3Client is the concrete, credential-bearing client handed back to mc's
+// commands. credChain is nil for clients built directly from an
+// already-resolved credValue (e.g. the STS client-grants path, which
+// manages its own refresh through stsCredentialsCache); when set, call
+// invalidates it on an auth rejection and re-resolves before the next
+// attempt.
+type s3Client struct {
+	url       string
+	creds     credValue
+	credChain *credentialsChain
+	debug     bool
+	policy    *policyAuthorizer
+}
+
+// newS3Client wires up an s3Client for url using the already-resolved
+// creds, gating mutating calls through globalPolicyConfig when set.
+func newS3Client(url string, creds credValue, debug bool) (client, error) {
+	return newS3ClientForAlias("", url, creds, debug)
+}
+
+// newS3ClientForAlias is newS3Client's alias-aware counterpart: mutating
+// calls are gated through globalPolicyConfig.forAlias(alias), so a
+// `policy.aliases.<alias>` override in config.json actually takes
+// effect.
+func newS3ClientForAlias(alias, url string, creds credValue, debug bool) (client, error) {
+	return &s3Client{
+		url:    url,
+		creds:  creds,
+		debug:  debug,
+		policy: newPolicyAuthorizer(globalPolicyConfig.forAlias(alias)),
+	}, nil
+}
+
+func (c *s3Client) URL() string { return c.url }
+
+// call authorizes mutating actions against the policy hook, then drives
+// do through retryWithBackoff so every network-facing method shares the
+// same retry behaviour. A 401/403 from do invalidates credChain (when
+// set) and re-resolves it for one further attempt, so credentials that
+// the server has started rejecting (e.g. a rotated key or an STS
+// credential revoked early) are refreshed instead of failing forever.
+func (c *s3Client) call(action, resource string, mutating bool, do func() error) error {
+	if mutating {
+		if err := c.policy.Authorize(c.creds.AccessKeyID, action, resource, nil); err != nil {
+			return err
+		}
+	}
+	err := retryWithBackoff(context.Background(), do)
+	if isAuthError(err) && c.credChain != nil {
+		c.credChain.Invalidate()
+		if value, rerr := c.credChain.Retrieve(); rerr == nil {
+			c.creds = value
+			err = retryWithBackoff(context.Background(), do)
+		}
+	}
+	return err
+}
+
+func (c *s3Client) GetObject(bucket, object string) error {
+	return c.call("GetObject", bucket+"/"+object, false, func() error { return nil })
+}
+
+func (c *s3Client) PutObject(bucket, object string) error {
+	return c.call("PutObject", bucket+"/"+object, true, func() error { return nil })
+}
+
+func (c *s3Client) ListObjects(bucket string) error {
+	return c.call("ListObjects", bucket, false, func() error { return nil })
+}
+
+func (c *s3Client) RemoveObject(bucket, object string) error {
+	return c.call("RemoveObject", bucket+"/"+object, true, func() error { return nil })
+}
+
+func (c *s3Client) StatObject(bucket, object string) error {
+	return c.call("StatObject", bucket+"/"+object, false, func() error { return nil })
+}
+
+func (c *s3Client) PutBucketPolicy(bucket string) error {
+	return c.call("PutBucketPolicy", bucket, true, func() error { return nil })
+}
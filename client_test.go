@@ -0,0 +1,71 @@
+/*
+ * Minio Client (C) 2014, 2015 Minio, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	"net/http"
+
+	. "github.com/minio/check"
+)
+
+// refreshingProvider is a credentialsProvider double whose Retrieve
+// result changes on each call, used to verify that a re-resolution after
+// Invalidate actually picks up fresh credentials rather than replaying
+// the rejected ones.
+type refreshingProvider struct {
+	calls int
+}
+
+func (p *refreshingProvider) Retrieve() (credValue, error) {
+	p.calls++
+	if p.calls == 1 {
+		return credValue{AccessKeyID: "stale"}, nil
+	}
+	return credValue{AccessKeyID: "fresh"}, nil
+}
+
+func (p *refreshingProvider) IsExpired() bool { return false }
+
+func (s *CmdTestSuite) TestS3ClientCallInvalidatesChainOnAuthError(c *C) {
+	provider := &refreshingProvider{calls: 1}
+	chain := &credentialsChain{providers: []credentialsProvider{provider}, current: provider}
+	cl := &s3Client{creds: credValue{AccessKeyID: "stale"}, credChain: chain, policy: newPolicyAuthorizer(policyConfig{})}
+
+	err := cl.call("GetObject", "bucket/key", false, func() error {
+		if cl.creds.AccessKeyID == "stale" {
+			return &httpStatusError{StatusCode: http.StatusForbidden}
+		}
+		return nil
+	})
+	c.Assert(err, IsNil)
+	c.Assert(cl.creds.AccessKeyID, Equals, "fresh")
+	c.Assert(provider.calls, Equals, 2)
+}
+
+func (s *CmdTestSuite) TestS3ClientCallGivesUpWhenRefreshStillRejected(c *C) {
+	stale := &fakeProvider{value: credValue{AccessKeyID: "stale"}}
+	chain := &credentialsChain{providers: []credentialsProvider{stale}, current: stale}
+	cl := &s3Client{creds: credValue{AccessKeyID: "stale"}, credChain: chain, policy: newPolicyAuthorizer(policyConfig{})}
+
+	attempts := 0
+	err := cl.call("GetObject", "bucket/key", false, func() error {
+		attempts++
+		return &httpStatusError{StatusCode: http.StatusForbidden}
+	})
+	c.Assert(err, FitsTypeOf, &httpStatusError{})
+	c.Assert(attempts, Equals, 2)
+}
@@ -38,11 +38,6 @@ type CmdTestSuite struct{}
 
 var _ = Suite(&CmdTestSuite{})
 
-func mustGetMcConfigDir() string {
-	dir, _ := getMcConfigDir()
-	return dir
-}
-
 func (s *CmdTestSuite) TestGetNewClient(c *C) {
 	_, err := getNewClient("http://example.com/bucket1", &hostConfig{}, false)
 	c.Assert(err, IsNil)
@@ -123,6 +118,16 @@ func (s *CmdTestSuite) TestGetMcConfigDir(c *C) {
 	c.Assert(mustGetMcConfigDir(), Equals, dir)
 }
 
+func (s *CmdTestSuite) TestGetMcConfigDirHonorsHomeOverride(c *C) {
+	oldHome := os.Getenv("HOME")
+	defer os.Setenv("HOME", oldHome)
+	os.Setenv("HOME", "/tmp/mc-home-override")
+
+	dir, err := getMcConfigDir()
+	c.Assert(err, IsNil)
+	c.Assert(dir, Equals, path.Join("/tmp/mc-home-override", ".mc/"))
+}
+
 func (s *CmdTestSuite) TestGetMcConfigPath(c *C) {
 	dir, err := getMcConfigPath()
 	c.Assert(err, IsNil)
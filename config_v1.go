@@ -0,0 +1,97 @@
+/*
+ * Minio Client (C) 2014, 2015 Minio, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	"os"
+	"os/user"
+	"path"
+	"runtime"
+
+	"github.com/minio/mc/pkg/quick"
+)
+
+// configV1 is the version 1 on-disk layout of ~/.mc/config.json. It only
+// ever grows a hostConfig of access/secret keys in a later revision, so
+// keep the fields here minimal.
+type configV1 struct {
+	Version string
+	Aliases map[string]string
+	Policy  policyConfig
+}
+
+// newConfigV1 returns a configV1 pre-populated with the aliases mc ships
+// out of the box.
+func newConfigV1() *configV1 {
+	conf := new(configV1)
+	conf.Version = "1.0.0"
+	conf.Aliases = map[string]string{
+		"s3":        "https://s3.amazonaws.com",
+		"play":      "http://play.minio.io:9000",
+		"localhost": "http://localhost:9000",
+	}
+	return conf
+}
+
+// newConfig returns a quick.Config wrapping a freshly initialized configV1.
+func newConfig() (quick.Config, error) {
+	return quick.New(newConfigV1())
+}
+
+// getMcConfigDir returns the platform specific directory mc keeps its
+// config and session state under. $HOME, when set, takes precedence over
+// user.Current().HomeDir so tests (and users with a deliberately
+// overridden $HOME) land in a directory other than the real one.
+func getMcConfigDir() (string, error) {
+	home := os.Getenv("HOME")
+	if home == "" {
+		u, err := user.Current()
+		if err != nil {
+			return "", err
+		}
+		home = u.HomeDir
+	}
+	switch runtime.GOOS {
+	case "windows":
+		return path.Join(home, "mc/"), nil
+	default:
+		return path.Join(home, ".mc/"), nil
+	}
+}
+
+// mustGetMcConfigDir is like getMcConfigDir but swallows the error for
+// call sites that only need a default value.
+func mustGetMcConfigDir() string {
+	dir, _ := getMcConfigDir()
+	return dir
+}
+
+// getMcConfigPath returns the full path to mc's config.json.
+func getMcConfigPath() (string, error) {
+	dir, err := getMcConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return path.Join(dir, "config.json"), nil
+}
+
+// mustGetMcConfigPath is like getMcConfigPath but swallows the error for
+// call sites that only need a default value (e.g. cli flag defaults).
+func mustGetMcConfigPath() string {
+	path, _ := getMcConfigPath()
+	return path
+}
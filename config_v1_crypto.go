@@ -0,0 +1,323 @@
+/*
+ * Minio Client (C) 2014, 2015 Minio, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"io"
+	"io/ioutil"
+	"os"
+
+	"golang.org/x/crypto/argon2"
+
+	"github.com/minio/mc/pkg/quick"
+)
+
+// errInvalidPassphrase is returned when a sealed config cannot be opened
+// with the supplied passphrase.
+var errInvalidPassphrase = errors.New("incorrect passphrase, unable to decrypt config")
+
+// mcConfigPassphraseEnv is the environment variable mc reads the config
+// encryption passphrase from when one isn't supplied on the command line.
+const mcConfigPassphraseEnv = "MC_CONFIG_PASSPHRASE"
+
+// sealedConfigV1 is the on-disk envelope for an encrypted config.json. The
+// plaintext configV1 JSON is sealed with AES-256-GCM using a key derived
+// from the user's passphrase via argon2id, the same construction the
+// Minio server uses to protect its own config blob.
+type sealedConfigV1 struct {
+	Sealed bool   `json:"sealed"`
+	Salt   string `json:"salt"`
+	Nonce  string `json:"nonce"`
+	Data   string `json:"data"`
+}
+
+const (
+	configSaltSize   = 32
+	argon2idTime     = 1
+	argon2idMemory   = 64 * 1024
+	argon2idThreads  = 4
+	argon2idKeyBytes = 32
+)
+
+// deriveConfigKey derives a 32 byte AES-256 key from password and salt
+// using argon2id.
+func deriveConfigKey(password string, salt []byte) []byte {
+	return argon2.IDKey([]byte(password), salt, argon2idTime, argon2idMemory, argon2idThreads, argon2idKeyBytes)
+}
+
+// sealConfigData encrypts plaintext config.json bytes with password,
+// returning the sealed envelope ready to be written to disk.
+func sealConfigData(plain []byte, password string) (*sealedConfigV1, error) {
+	salt := make([]byte, configSaltSize)
+	if _, err := io.ReadFull(rand.Reader, salt); err != nil {
+		return nil, err
+	}
+	key := deriveConfigKey(password, salt)
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	gcm, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return nil, err
+	}
+	ciphertext := gcm.Seal(nil, nonce, plain, nil)
+	return &sealedConfigV1{
+		Sealed: true,
+		Salt:   base64.StdEncoding.EncodeToString(salt),
+		Nonce:  base64.StdEncoding.EncodeToString(nonce),
+		Data:   base64.StdEncoding.EncodeToString(ciphertext),
+	}, nil
+}
+
+// openConfigData decrypts a sealed envelope back into the plaintext
+// config.json bytes it was created from.
+func openConfigData(sealed *sealedConfigV1, password string) ([]byte, error) {
+	salt, err := base64.StdEncoding.DecodeString(sealed.Salt)
+	if err != nil {
+		return nil, err
+	}
+	nonce, err := base64.StdEncoding.DecodeString(sealed.Nonce)
+	if err != nil {
+		return nil, err
+	}
+	ciphertext, err := base64.StdEncoding.DecodeString(sealed.Data)
+	if err != nil {
+		return nil, err
+	}
+	key := deriveConfigKey(password, salt)
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	gcm, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+	plain, err := gcm.Open(nil, nonce, ciphertext, nil)
+	if err != nil {
+		return nil, errInvalidPassphrase
+	}
+	return plain, nil
+}
+
+// isSealedConfig reports whether raw already holds a sealedConfigV1
+// envelope, as opposed to a plaintext configV1.
+func isSealedConfig(raw []byte) bool {
+	var sealed sealedConfigV1
+	if err := json.Unmarshal(raw, &sealed); err != nil {
+		return false
+	}
+	return sealed.Sealed
+}
+
+// newEncryptedConfig behaves like newConfig but returns a quick.Config
+// whose Save writes an AES-256-GCM sealed config.json instead of plaintext.
+func newEncryptedConfig(password string) (quick.Config, error) {
+	qc, err := quick.New(newConfigV1())
+	if err != nil {
+		return nil, err
+	}
+	return &encryptedConfig{Config: qc, password: password}, nil
+}
+
+// encryptedConfig wraps a quick.Config, transparently sealing/opening the
+// backing file with a password-derived key.
+type encryptedConfig struct {
+	quick.Config
+	password string
+}
+
+// Save marshals the wrapped config in memory, seals it, and writes only
+// the sealed envelope to filename.
+func (e *encryptedConfig) Save(filename string) error {
+	plain, err := json.Marshal(e.Config.Data())
+	if err != nil {
+		return err
+	}
+	sealed, err := sealConfigData(plain, e.password)
+	if err != nil {
+		return err
+	}
+	out, err := json.Marshal(sealed)
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(filename, out, 0600)
+}
+
+// Load reads filename, opens the sealed envelope with e.password and
+// loads the resulting plaintext into the wrapped config.
+func (e *encryptedConfig) Load(filename string) error {
+	raw, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return err
+	}
+	var sealed sealedConfigV1
+	if err := json.Unmarshal(raw, &sealed); err != nil || !sealed.Sealed {
+		return errors.New("config.json is not an encrypted config")
+	}
+	plain, err := openConfigData(&sealed, e.password)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(plain, e.Config.Data())
+}
+
+// LoadEncrypted loads an mc config.json that may be either a plaintext v1
+// config or one sealed with password. A plaintext file is loaded as is
+// and the file on disk is not changed. The returned value is a plain
+// quick.Config, so its Save writes plaintext; SaveEncrypted writes it
+// sealed.
+func LoadEncrypted(filename, password string) (quick.Config, error) {
+	raw, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	qc, err := quick.New(newConfigV1())
+	if err != nil {
+		return nil, err
+	}
+	if !isSealedConfig(raw) {
+		// Backward compatible path: existing unencrypted v1 config.
+		if err := qc.Load(filename); err != nil {
+			return nil, err
+		}
+		return qc, nil
+	}
+	var sealed sealedConfigV1
+	if err := json.Unmarshal(raw, &sealed); err != nil {
+		return nil, err
+	}
+	plain, err := openConfigData(&sealed, password)
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(plain, qc.Data()); err != nil {
+		return nil, err
+	}
+	return qc, nil
+}
+
+// SaveEncrypted seals conf's data with password and writes it to filename.
+func SaveEncrypted(filename string, conf quick.Config, password string) error {
+	plain, err := json.Marshal(conf.Data())
+	if err != nil {
+		return err
+	}
+	sealed, err := sealConfigData(plain, password)
+	if err != nil {
+		return err
+	}
+	out, err := json.Marshal(sealed)
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(filename, out, 0600)
+}
+
+// migrateConfigToEncrypted seals an existing plaintext config.json at
+// filename in place using password. It is a no-op if the file is already
+// sealed.
+func migrateConfigToEncrypted(filename, password string) error {
+	raw, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return err
+	}
+	if isSealedConfig(raw) {
+		return nil
+	}
+	sealed, err := sealConfigData(raw, password)
+	if err != nil {
+		return err
+	}
+	out, err := json.Marshal(sealed)
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(filename, out, 0600)
+}
+
+// migrateConfigToPlaintext reverses migrateConfigToEncrypted, writing the
+// decrypted plaintext config.json back to filename.
+func migrateConfigToPlaintext(filename, password string) error {
+	raw, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return err
+	}
+	if !isSealedConfig(raw) {
+		return nil
+	}
+	var sealed sealedConfigV1
+	if err := json.Unmarshal(raw, &sealed); err != nil {
+		return err
+	}
+	plain, err := openConfigData(&sealed, password)
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(filename, plain, 0600)
+}
+
+// configPassphraseFromEnv returns the passphrase configured via
+// MC_CONFIG_PASSPHRASE, or "" if unset.
+func configPassphraseFromEnv() string {
+	return os.Getenv(mcConfigPassphraseEnv)
+}
+
+// runConfigEncryptCmd implements `mc config encrypt`: seals the user's
+// config.json in place with password (falling back to
+// MC_CONFIG_PASSPHRASE when password is empty).
+func runConfigEncryptCmd(password string) error {
+	if password == "" {
+		password = configPassphraseFromEnv()
+	}
+	if password == "" {
+		return errors.New("no passphrase provided, set MC_CONFIG_PASSPHRASE or pass one explicitly")
+	}
+	configFile, err := getMcConfigPath()
+	if err != nil {
+		return err
+	}
+	return migrateConfigToEncrypted(configFile, password)
+}
+
+// runConfigDecryptCmd implements `mc config decrypt`: reverses
+// runConfigEncryptCmd, writing the plaintext config.json back out.
+func runConfigDecryptCmd(password string) error {
+	if password == "" {
+		password = configPassphraseFromEnv()
+	}
+	if password == "" {
+		return errors.New("no passphrase provided, set MC_CONFIG_PASSPHRASE or pass one explicitly")
+	}
+	configFile, err := getMcConfigPath()
+	if err != nil {
+		return err
+	}
+	return migrateConfigToPlaintext(configFile, password)
+}
@@ -0,0 +1,94 @@
+/*
+ * Minio Client (C) 2014, 2015 Minio, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path"
+
+	. "github.com/minio/check"
+)
+
+func (s *CmdTestSuite) TestConfigEncryptedRoundTrip(c *C) {
+	root, err := ioutil.TempDir(os.TempDir(), "mc-")
+	c.Assert(err, IsNil)
+	defer os.RemoveAll(root)
+
+	configFile := path.Join(root, "config.json")
+	conf, err := newEncryptedConfig("s3cr3t")
+	c.Assert(err, IsNil)
+	c.Assert(conf.Save(configFile), IsNil)
+
+	loaded, err := LoadEncrypted(configFile, "s3cr3t")
+	c.Assert(err, IsNil)
+	data := loaded.Data().(*configV1)
+	c.Assert(data.Aliases["s3"], Equals, "https://s3.amazonaws.com")
+}
+
+func (s *CmdTestSuite) TestConfigEncryptedWrongPassword(c *C) {
+	root, err := ioutil.TempDir(os.TempDir(), "mc-")
+	c.Assert(err, IsNil)
+	defer os.RemoveAll(root)
+
+	configFile := path.Join(root, "config.json")
+	conf, err := newEncryptedConfig("s3cr3t")
+	c.Assert(err, IsNil)
+	c.Assert(conf.Save(configFile), IsNil)
+
+	_, err = LoadEncrypted(configFile, "wrong-password")
+	c.Assert(err, Equals, errInvalidPassphrase)
+}
+
+func (s *CmdTestSuite) TestConfigEncryptedBackwardCompat(c *C) {
+	root, err := ioutil.TempDir(os.TempDir(), "mc-")
+	c.Assert(err, IsNil)
+	defer os.RemoveAll(root)
+
+	configFile := path.Join(root, "config.json")
+	conf, err := newConfig()
+	c.Assert(err, IsNil)
+	c.Assert(conf.Save(configFile), IsNil)
+
+	// LoadEncrypted must still read a plain, unencrypted v1 config file.
+	loaded, err := LoadEncrypted(configFile, "irrelevant")
+	c.Assert(err, IsNil)
+	data := loaded.Data().(*configV1)
+	c.Assert(data.Aliases["play"], Equals, "http://play.minio.io:9000")
+}
+
+func (s *CmdTestSuite) TestMigrateConfigToEncrypted(c *C) {
+	root, err := ioutil.TempDir(os.TempDir(), "mc-")
+	c.Assert(err, IsNil)
+	defer os.RemoveAll(root)
+
+	configFile := path.Join(root, "config.json")
+	conf, err := newConfig()
+	c.Assert(err, IsNil)
+	c.Assert(conf.Save(configFile), IsNil)
+
+	c.Assert(migrateConfigToEncrypted(configFile, "s3cr3t"), IsNil)
+
+	raw, err := ioutil.ReadFile(configFile)
+	c.Assert(err, IsNil)
+	c.Assert(isSealedConfig(raw), Equals, true)
+
+	loaded, err := LoadEncrypted(configFile, "s3cr3t")
+	c.Assert(err, IsNil)
+	data := loaded.Data().(*configV1)
+	c.Assert(data.Aliases["localhost"], Equals, "http://localhost:9000")
+}
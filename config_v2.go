@@ -0,0 +1,89 @@
+/*
+ * Minio Client (C) 2014, 2015 Minio, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	"github.com/minio/mc/pkg/quick"
+)
+
+// jwtSource describes where an STS alias gets the OAuth2/OIDC JWT it
+// exchanges for temporary credentials: either a fixed token endpoint with
+// a client id/secret, or a command whose stdout is the JWT.
+type jwtSource struct {
+	TokenURL     string
+	ClientID     string
+	ClientSecret string
+	Command      string
+}
+
+// aliasV2 is a single alias entry in configV2. At most one of Creds or
+// JWT is set: the former for long-lived keys, the latter for an STS
+// AssumeRoleWithClientGrants alias. The default aliases and those carried
+// over from v1 set neither.
+type aliasV2 struct {
+	URL   string
+	Creds *hostConfig `json:",omitempty"`
+	JWT   *jwtSource  `json:",omitempty"`
+}
+
+// isSTS reports whether this alias authenticates via
+// AssumeRoleWithClientGrants rather than static keys.
+func (a aliasV2) isSTS() bool {
+	return a.JWT != nil
+}
+
+// configV2 is the v2 on-disk config.json layout. It replaces configV1's
+// bare alias->URL map with aliasV2 entries so that an alias can carry
+// either static keys or an STS JWT source alongside its URL.
+type configV2 struct {
+	Version string
+	Aliases map[string]aliasV2
+	Policy  policyConfig
+}
+
+// newConfigV2 returns a configV2 pre-populated with the same default
+// aliases as newConfigV1, upgraded to the richer aliasV2 shape.
+func newConfigV2() *configV2 {
+	conf := new(configV2)
+	conf.Version = "2.0.0"
+	conf.Aliases = map[string]aliasV2{
+		"s3":        {URL: "https://s3.amazonaws.com"},
+		"play":      {URL: "http://play.minio.io:9000"},
+		"localhost": {URL: "http://localhost:9000"},
+	}
+	return conf
+}
+
+// newConfigV2FromV1 upgrades a loaded configV1 to configV2, carrying the
+// existing aliases forward as plain URL entries (no credentials were
+// ever stored in v1) and carrying the `policy` section forward as-is.
+func newConfigV2FromV1(v1 *configV1) *configV2 {
+	conf := new(configV2)
+	conf.Version = "2.0.0"
+	conf.Aliases = make(map[string]aliasV2, len(v1.Aliases))
+	for name, url := range v1.Aliases {
+		conf.Aliases[name] = aliasV2{URL: url}
+	}
+	conf.Policy = v1.Policy
+	return conf
+}
+
+// newConfigV2Config returns a quick.Config wrapping a freshly initialized
+// configV2.
+func newConfigV2Config() (quick.Config, error) {
+	return quick.New(newConfigV2())
+}
@@ -0,0 +1,277 @@
+/*
+ * Minio Client (C) 2014, 2015 Minio, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"os/user"
+	"path"
+	"strings"
+	"time"
+)
+
+// errNoCredentials is returned by a credentialsProvider that has no
+// credentials to offer, letting the chain fall through to the next one.
+var errNoCredentials = errors.New("no credentials available")
+
+// credValue is the tuple of keys a credentialsProvider resolves to.
+type credValue struct {
+	AccessKeyID     string
+	SecretAccessKey string
+	SessionToken    string
+	ProviderName    string
+}
+
+func (v credValue) isEmpty() bool {
+	return v.AccessKeyID == "" && v.SecretAccessKey == ""
+}
+
+// credentialsProvider is implemented by every source of credentials the
+// chain consults, in precedence order.
+type credentialsProvider interface {
+	Retrieve() (credValue, error)
+	IsExpired() bool
+}
+
+// credentialsChain tries each provider in order, caching the first
+// successful, non-expired result and re-resolving once it expires or is
+// explicitly invalidated (e.g. after a 401/403 from the server).
+type credentialsChain struct {
+	providers []credentialsProvider
+	current   credentialsProvider
+	value     credValue
+}
+
+// newCredentialsChain builds the standard mc provider precedence:
+// explicit hostConfig, environment, shared AWS credentials file, shared
+// mc credentials file, then EC2/ECS instance metadata.
+func newCredentialsChain(auth *hostConfig) *credentialsChain {
+	providers := []credentialsProvider{
+		&staticProvider{auth: auth},
+		&envProvider{},
+		&sharedFileProvider{path: defaultAWSCredentialsFile(), profile: envOrDefault("AWS_PROFILE", "default")},
+		&sharedFileProvider{path: defaultMcCredentialsFile(), profile: "default"},
+		&ec2MetadataProvider{client: &http.Client{Timeout: 5 * time.Second}, endpoint: ec2MetadataEndpoint},
+	}
+	return &credentialsChain{providers: providers}
+}
+
+// Retrieve returns the highest precedence credentials currently
+// available. Providers that return errNoCredentials are skipped; if none
+// of them have credentials, an empty (anonymous) credValue is returned
+// rather than an error so that callers can still talk to endpoints that
+// don't require auth.
+func (chain *credentialsChain) Retrieve() (credValue, error) {
+	if chain.current != nil && !chain.current.IsExpired() {
+		return chain.value, nil
+	}
+	for _, p := range chain.providers {
+		value, err := p.Retrieve()
+		if err != nil || p.IsExpired() {
+			continue
+		}
+		chain.current = p
+		chain.value = value
+		return value, nil
+	}
+	return credValue{}, nil
+}
+
+// Invalidate forces the next Retrieve to re-evaluate the chain from the
+// top, used after the server rejects the cached credentials with a
+// 401/403.
+func (chain *credentialsChain) Invalidate() {
+	chain.current = nil
+}
+
+// staticProvider wraps the hostConfig passed in explicitly by the
+// caller (e.g. an alias configured in config.json).
+type staticProvider struct {
+	auth *hostConfig
+}
+
+func (p *staticProvider) Retrieve() (credValue, error) {
+	if p.auth == nil || p.auth.AccessKeyID == "" {
+		return credValue{}, errNoCredentials
+	}
+	return credValue{
+		AccessKeyID:     p.auth.AccessKeyID,
+		SecretAccessKey: p.auth.SecretAccessKey,
+		ProviderName:    "StaticProvider",
+	}, nil
+}
+
+func (p *staticProvider) IsExpired() bool { return false }
+
+// envProvider reads MC_ACCESS_KEY/MC_SECRET_KEY, falling back to the AWS
+// CLI's AWS_ACCESS_KEY_ID/AWS_SECRET_ACCESS_KEY.
+type envProvider struct{}
+
+func (p *envProvider) Retrieve() (credValue, error) {
+	accessKey := envOrDefault("MC_ACCESS_KEY", os.Getenv("AWS_ACCESS_KEY_ID"))
+	secretKey := envOrDefault("MC_SECRET_KEY", os.Getenv("AWS_SECRET_ACCESS_KEY"))
+	if accessKey == "" || secretKey == "" {
+		return credValue{}, errNoCredentials
+	}
+	return credValue{
+		AccessKeyID:     accessKey,
+		SecretAccessKey: secretKey,
+		SessionToken:    os.Getenv("AWS_SESSION_TOKEN"),
+		ProviderName:    "EnvProvider",
+	}, nil
+}
+
+func (p *envProvider) IsExpired() bool { return false }
+
+// sharedFileProvider reads an ini-style shared credentials file such as
+// ~/.aws/credentials or mc's own credentials file, selecting profile.
+type sharedFileProvider struct {
+	path    string
+	profile string
+}
+
+func (p *sharedFileProvider) Retrieve() (credValue, error) {
+	f, err := os.Open(p.path)
+	if err != nil {
+		return credValue{}, errNoCredentials
+	}
+	defer f.Close()
+
+	section := ""
+	keys := map[string]string{}
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") || strings.HasPrefix(line, ";") {
+			continue
+		}
+		if strings.HasPrefix(line, "[") && strings.HasSuffix(line, "]") {
+			section = strings.TrimSuffix(strings.TrimPrefix(line, "["), "]")
+			continue
+		}
+		if section != p.profile {
+			continue
+		}
+		parts := strings.SplitN(line, "=", 2)
+		if len(parts) != 2 {
+			continue
+		}
+		keys[strings.TrimSpace(parts[0])] = strings.TrimSpace(parts[1])
+	}
+	accessKey := keys["aws_access_key_id"]
+	secretKey := keys["aws_secret_access_key"]
+	if accessKey == "" || secretKey == "" {
+		return credValue{}, errNoCredentials
+	}
+	return credValue{
+		AccessKeyID:     accessKey,
+		SecretAccessKey: secretKey,
+		SessionToken:    keys["aws_session_token"],
+		ProviderName:    "SharedFileProvider",
+	}, nil
+}
+
+func (p *sharedFileProvider) IsExpired() bool { return false }
+
+// ec2MetadataProvider retrieves temporary credentials off EC2/ECS
+// instance metadata, refreshing ahead of their expiration.
+type ec2MetadataProvider struct {
+	client     *http.Client
+	endpoint   string
+	expiration time.Time
+}
+
+const ec2MetadataEndpoint = "http://169.254.169.254/latest/meta-data/iam/security-credentials/"
+
+// ec2MetadataCredentials is the JSON document returned by
+// ec2MetadataEndpoint+"<role>".
+type ec2MetadataCredentials struct {
+	AccessKeyID     string
+	SecretAccessKey string
+	Token           string
+	Expiration      time.Time
+}
+
+func (p *ec2MetadataProvider) Retrieve() (credValue, error) {
+	resp, err := p.client.Get(p.endpoint)
+	if err != nil {
+		return credValue{}, errNoCredentials
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return credValue{}, errNoCredentials
+	}
+	role, err := ioutil.ReadAll(resp.Body)
+	if err != nil || len(role) == 0 {
+		return credValue{}, errNoCredentials
+	}
+
+	credResp, err := p.client.Get(p.endpoint + string(role))
+	if err != nil {
+		return credValue{}, errNoCredentials
+	}
+	defer credResp.Body.Close()
+	if credResp.StatusCode != http.StatusOK {
+		return credValue{}, errNoCredentials
+	}
+
+	var creds ec2MetadataCredentials
+	if err := json.NewDecoder(credResp.Body).Decode(&creds); err != nil {
+		return credValue{}, errNoCredentials
+	}
+	if creds.AccessKeyID == "" || creds.SecretAccessKey == "" {
+		return credValue{}, errNoCredentials
+	}
+
+	p.expiration = creds.Expiration
+	return credValue{
+		AccessKeyID:     creds.AccessKeyID,
+		SecretAccessKey: creds.SecretAccessKey,
+		SessionToken:    creds.Token,
+		ProviderName:    "EC2MetadataProvider",
+	}, nil
+}
+
+// IsExpired reports true once the cached metadata credentials are within
+// a minute of Expiration, so Retrieve refreshes them ahead of time.
+func (p *ec2MetadataProvider) IsExpired() bool {
+	return time.Now().Add(time.Minute).After(p.expiration)
+}
+
+func envOrDefault(key, def string) string {
+	if v := os.Getenv(key); v != "" {
+		return v
+	}
+	return def
+}
+
+func defaultAWSCredentialsFile() string {
+	u, err := user.Current()
+	if err != nil {
+		return ""
+	}
+	return path.Join(u.HomeDir, ".aws", "credentials")
+}
+
+func defaultMcCredentialsFile() string {
+	return path.Join(mustGetMcConfigDir(), "credentials")
+}
@@ -0,0 +1,185 @@
+/*
+ * Minio Client (C) 2014, 2015 Minio, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path"
+	"time"
+
+	. "github.com/minio/check"
+)
+
+// fakeProvider is a credentialsProvider double used to verify chain
+// precedence and expiry behaviour without touching the real environment.
+type fakeProvider struct {
+	value   credValue
+	err     error
+	expired bool
+}
+
+func (p *fakeProvider) Retrieve() (credValue, error) { return p.value, p.err }
+func (p *fakeProvider) IsExpired() bool              { return p.expired }
+
+func (s *CmdTestSuite) TestCredentialsChainPrecedence(c *C) {
+	chain := &credentialsChain{providers: []credentialsProvider{
+		&fakeProvider{err: errNoCredentials},
+		&fakeProvider{value: credValue{AccessKeyID: "second", SecretAccessKey: "secondsecret"}},
+		&fakeProvider{value: credValue{AccessKeyID: "third", SecretAccessKey: "thirdsecret"}},
+	}}
+	value, err := chain.Retrieve()
+	c.Assert(err, IsNil)
+	c.Assert(value.AccessKeyID, Equals, "second")
+}
+
+func (s *CmdTestSuite) TestCredentialsChainFallthroughOnNoCredentials(c *C) {
+	chain := &credentialsChain{providers: []credentialsProvider{
+		&fakeProvider{err: errNoCredentials},
+		&fakeProvider{err: errNoCredentials},
+	}}
+	value, err := chain.Retrieve()
+	c.Assert(err, IsNil)
+	c.Assert(value.isEmpty(), Equals, true)
+}
+
+func (s *CmdTestSuite) TestCredentialsChainRefreshesOnExpiry(c *C) {
+	expired := &fakeProvider{value: credValue{AccessKeyID: "stale"}, expired: true}
+	fresh := &fakeProvider{value: credValue{AccessKeyID: "fresh", SecretAccessKey: "freshsecret"}}
+	chain := &credentialsChain{providers: []credentialsProvider{expired, fresh}, current: expired}
+	value, err := chain.Retrieve()
+	c.Assert(err, IsNil)
+	c.Assert(value.AccessKeyID, Equals, "fresh")
+}
+
+func (s *CmdTestSuite) TestEnvProviderPrecedence(c *C) {
+	os.Setenv("MC_ACCESS_KEY", "mckey")
+	os.Setenv("MC_SECRET_KEY", "mcsecret")
+	os.Setenv("AWS_ACCESS_KEY_ID", "awskey")
+	os.Setenv("AWS_SECRET_ACCESS_KEY", "awssecret")
+	defer os.Unsetenv("MC_ACCESS_KEY")
+	defer os.Unsetenv("MC_SECRET_KEY")
+	defer os.Unsetenv("AWS_ACCESS_KEY_ID")
+	defer os.Unsetenv("AWS_SECRET_ACCESS_KEY")
+
+	p := &envProvider{}
+	value, err := p.Retrieve()
+	c.Assert(err, IsNil)
+	c.Assert(value.AccessKeyID, Equals, "mckey")
+}
+
+func (s *CmdTestSuite) TestSharedFileProvider(c *C) {
+	root, err := ioutil.TempDir(os.TempDir(), "mc-")
+	c.Assert(err, IsNil)
+	defer os.RemoveAll(root)
+
+	credsFile := path.Join(root, "credentials")
+	contents := "[default]\naws_access_key_id = filekey\naws_secret_access_key = filesecret\n"
+	c.Assert(ioutil.WriteFile(credsFile, []byte(contents), 0600), IsNil)
+
+	p := &sharedFileProvider{path: credsFile, profile: "default"}
+	value, err := p.Retrieve()
+	c.Assert(err, IsNil)
+	c.Assert(value.AccessKeyID, Equals, "filekey")
+	c.Assert(value.SecretAccessKey, Equals, "filesecret")
+}
+
+func (s *CmdTestSuite) TestEC2MetadataProviderExpiry(c *C) {
+	p := &ec2MetadataProvider{expiration: time.Now().Add(-time.Hour)}
+	c.Assert(p.IsExpired(), Equals, true)
+
+	p.expiration = time.Now().Add(time.Hour)
+	c.Assert(p.IsExpired(), Equals, false)
+}
+
+func (s *CmdTestSuite) TestEC2MetadataProviderRetrieve(c *C) {
+	expiry := time.Now().Add(time.Hour).Truncate(time.Second)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/" {
+			fmt.Fprint(w, "instance-role")
+			return
+		}
+		fmt.Fprintf(w, `{"AccessKeyID":"metakey","SecretAccessKey":"metasecret","Token":"metatoken","Expiration":%q}`,
+			expiry.UTC().Format(time.RFC3339))
+	}))
+	defer srv.Close()
+
+	p := &ec2MetadataProvider{client: srv.Client(), endpoint: srv.URL + "/"}
+	value, err := p.Retrieve()
+	c.Assert(err, IsNil)
+	c.Assert(value.AccessKeyID, Equals, "metakey")
+	c.Assert(value.SecretAccessKey, Equals, "metasecret")
+	c.Assert(value.SessionToken, Equals, "metatoken")
+	c.Assert(p.expiration.Equal(expiry.UTC()), Equals, true)
+	c.Assert(p.IsExpired(), Equals, false)
+}
+
+// closeTrackingBody wraps an io.ReadCloser and records whether Close was
+// called, so a test can assert a response body was drained/closed on
+// every return path, not just the happy one.
+type closeTrackingBody struct {
+	io.ReadCloser
+	closed *bool
+}
+
+func (b *closeTrackingBody) Close() error {
+	*b.closed = true
+	return b.ReadCloser.Close()
+}
+
+func (s *CmdTestSuite) TestEC2MetadataProviderClosesBodyOnNonOKRoleStatus(c *C) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+		fmt.Fprint(w, "boom")
+	}))
+	defer srv.Close()
+
+	var closed bool
+	client := srv.Client()
+	client.Transport = &closeTrackingTransport{base: http.DefaultTransport, closed: &closed}
+
+	p := &ec2MetadataProvider{client: client, endpoint: srv.URL + "/"}
+	_, err := p.Retrieve()
+	c.Assert(err, Equals, errNoCredentials)
+	c.Assert(closed, Equals, true)
+}
+
+// closeTrackingTransport wraps every response body it returns in a
+// closeTrackingBody.
+type closeTrackingTransport struct {
+	base   http.RoundTripper
+	closed *bool
+}
+
+func (t *closeTrackingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	resp, err := t.base.RoundTrip(req)
+	if err != nil {
+		return resp, err
+	}
+	resp.Body = &closeTrackingBody{ReadCloser: resp.Body, closed: t.closed}
+	return resp, nil
+}
+
+func (s *CmdTestSuite) TestEC2MetadataProviderRetrieveNoService(c *C) {
+	p := &ec2MetadataProvider{client: &http.Client{Timeout: 50 * time.Millisecond}, endpoint: "http://127.0.0.1:0/"}
+	_, err := p.Retrieve()
+	c.Assert(err, Equals, errNoCredentials)
+}
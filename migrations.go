@@ -0,0 +1,299 @@
+/*
+ * Minio Client (C) 2014, 2015 Minio, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/url"
+	"os"
+	"path"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/minio/mc/pkg/quick"
+)
+
+// migration upgrades a config.json from one version to the next. migrate
+// receives the config already loaded at "from" and returns the config at
+// "to", or an error if the upgrade can't be performed.
+type migration struct {
+	from, to string
+	migrate  func(quick.Config) (quick.Config, error)
+}
+
+// migrations lists every registered upgrade in order. Index i migrates
+// from migrations[i].from to migrations[i].to; the chain is expected to
+// be contiguous (migrations[i].to == migrations[i+1].from).
+var migrations = []migration{
+	{
+		from: "1.0.0",
+		to:   "2.0.0",
+		migrate: func(qc quick.Config) (quick.Config, error) {
+			v1, ok := qc.Data().(*configV1)
+			if !ok {
+				return nil, fmt.Errorf("migrate 1.0.0->2.0.0: unexpected config type %T", qc.Data())
+			}
+			return quick.New(newConfigV2FromV1(v1))
+		},
+	},
+}
+
+// migrationHistoryDir returns ~/.mc/history, creating it if necessary.
+func migrationHistoryDir() (string, error) {
+	dir := path.Join(mustGetMcConfigDir(), "history")
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return "", err
+	}
+	return dir, nil
+}
+
+// encodeSnapshotVersion makes version safe to use as a single path
+// segment in a dot-delimited snapshot filename, since semver versions
+// (e.g. "1.0.0") contain dots themselves.
+func encodeSnapshotVersion(version string) string {
+	return strings.ReplaceAll(version, ".", "_")
+}
+
+// decodeSnapshotVersion reverses encodeSnapshotVersion.
+func decodeSnapshotVersion(encoded string) string {
+	return strings.ReplaceAll(encoded, "_", ".")
+}
+
+// snapshotConfig copies the raw bytes at configFile into
+// ~/.mc/history/config.<version>.<timestamp>.json and returns the
+// snapshot's path.
+func snapshotConfig(configFile, version string, timestamp time.Time) (string, error) {
+	historyDir, err := migrationHistoryDir()
+	if err != nil {
+		return "", err
+	}
+	raw, err := ioutil.ReadFile(configFile)
+	if err != nil {
+		return "", err
+	}
+	snapshotPath := path.Join(historyDir, fmt.Sprintf("config.%s.%d.json", encodeSnapshotVersion(version), timestamp.Unix()))
+	if err := ioutil.WriteFile(snapshotPath, raw, 0600); err != nil {
+		return "", err
+	}
+	return snapshotPath, nil
+}
+
+// validateConfigV1 enforces the invariants a config must hold before
+// migrateConfig will let it overwrite config.json: every alias name must
+// satisfy isValidAliasName and every alias URL must parse.
+func validateConfigV1(v1 *configV1) error {
+	for name, rawURL := range v1.Aliases {
+		if !isValidAliasName(name) {
+			return fmt.Errorf("invalid alias name %q", name)
+		}
+		if _, err := url.Parse(rawURL); err != nil {
+			return fmt.Errorf("invalid alias url %q for %q: %v", rawURL, name, err)
+		}
+	}
+	return nil
+}
+
+// validateConfigV2 is validateConfigV1's counterpart for the v2 alias
+// shape.
+func validateConfigV2(v2 *configV2) error {
+	for name, alias := range v2.Aliases {
+		if !isValidAliasName(name) {
+			return fmt.Errorf("invalid alias name %q", name)
+		}
+		if _, err := url.Parse(alias.URL); err != nil {
+			return fmt.Errorf("invalid alias url %q for %q: %v", alias.URL, name, err)
+		}
+	}
+	return nil
+}
+
+// validateConfig dispatches to the validator for qc's concrete type.
+func validateConfig(qc quick.Config) error {
+	switch data := qc.Data().(type) {
+	case *configV1:
+		return validateConfigV1(data)
+	case *configV2:
+		return validateConfigV2(data)
+	default:
+		return fmt.Errorf("migrate: no validator registered for %T", data)
+	}
+}
+
+// configVersion reads the bare Version field out of configFile without
+// committing to a concrete config type.
+func configVersion(configFile string) (string, error) {
+	raw, err := ioutil.ReadFile(configFile)
+	if err != nil {
+		return "", err
+	}
+	var probe struct{ Version string }
+	if err := json.Unmarshal(raw, &probe); err != nil {
+		return "", err
+	}
+	return probe.Version, nil
+}
+
+// loadConfigAtVersion loads configFile into the quick.Config matching
+// version.
+func loadConfigAtVersion(configFile, version string) (quick.Config, error) {
+	var qc quick.Config
+	var err error
+	switch version {
+	case "1.0.0":
+		qc, err = quick.New(newConfigV1())
+	case "2.0.0":
+		qc, err = quick.New(newConfigV2())
+	default:
+		return nil, fmt.Errorf("migrate: unknown config version %q", version)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := qc.Load(configFile); err != nil {
+		return nil, err
+	}
+	return qc, nil
+}
+
+// migrateConfig runs every registered migration needed to bring
+// configFile up to the newest known version, snapshotting the config
+// before each step into ~/.mc/history. A result that fails validation is
+// never written, so config.json is left unchanged; if writing a valid
+// result fails, the pre-migration snapshot is restored.
+func migrateConfig(configFile string) error {
+	version, err := configVersion(configFile)
+	if err != nil {
+		return err
+	}
+
+	sort.SliceStable(migrations, func(i, j int) bool { return migrations[i].from < migrations[j].from })
+
+	applied := false
+	for _, m := range migrations {
+		if m.from != version {
+			continue
+		}
+		applied = true
+
+		preSnapshot, err := snapshotConfig(configFile, m.from, time.Now())
+		if err != nil {
+			return err
+		}
+
+		qc, err := loadConfigAtVersion(configFile, m.from)
+		if err != nil {
+			return err
+		}
+		upgraded, err := m.migrate(qc)
+		if err != nil {
+			return err
+		}
+		if err := validateConfig(upgraded); err != nil {
+			return fmt.Errorf("migrate %s->%s: %v (config left unchanged)", m.from, m.to, err)
+		}
+		if err := upgraded.Save(configFile); err != nil {
+			// Validation passed but the write failed: restore the
+			// pre-migration snapshot rather than leave a half written
+			// config.json behind.
+			raw, readErr := ioutil.ReadFile(preSnapshot)
+			if readErr == nil {
+				ioutil.WriteFile(configFile, raw, 0600)
+			}
+			return err
+		}
+
+		version = m.to
+	}
+	if !applied {
+		return nil
+	}
+	return migrateConfig(configFile)
+}
+
+// configHistoryEntry describes one snapshot under ~/.mc/history.
+type configHistoryEntry struct {
+	ID      string
+	Version string
+	Path    string
+}
+
+// listConfigHistory returns every snapshot under ~/.mc/history, oldest
+// first.
+func listConfigHistory() ([]configHistoryEntry, error) {
+	historyDir, err := migrationHistoryDir()
+	if err != nil {
+		return nil, err
+	}
+	files, err := ioutil.ReadDir(historyDir)
+	if err != nil {
+		return nil, err
+	}
+	var entries []configHistoryEntry
+	for _, f := range files {
+		// Filenames are "config.<version>.<timestamp>.json".
+		parts := strings.Split(f.Name(), ".")
+		if len(parts) != 4 || parts[0] != "config" || parts[3] != "json" {
+			continue
+		}
+		entries = append(entries, configHistoryEntry{
+			ID:      parts[1] + "." + parts[2],
+			Version: decodeSnapshotVersion(parts[1]),
+			Path:    path.Join(historyDir, f.Name()),
+		})
+	}
+	return entries, nil
+}
+
+// restoreConfigHistory overwrites configFile with the snapshot
+// identified by id (as returned by listConfigHistory).
+func restoreConfigHistory(configFile, id string) error {
+	entries, err := listConfigHistory()
+	if err != nil {
+		return err
+	}
+	for _, e := range entries {
+		if e.ID == id {
+			raw, err := ioutil.ReadFile(e.Path)
+			if err != nil {
+				return err
+			}
+			return ioutil.WriteFile(configFile, raw, 0600)
+		}
+	}
+	return fmt.Errorf("no config history entry %q", id)
+}
+
+// clearConfigHistory removes every snapshot under ~/.mc/history.
+func clearConfigHistory() error {
+	historyDir, err := migrationHistoryDir()
+	if err != nil {
+		return err
+	}
+	entries, err := ioutil.ReadDir(historyDir)
+	if err != nil {
+		return err
+	}
+	for _, e := range entries {
+		if err := os.Remove(path.Join(historyDir, e.Name())); err != nil {
+			return err
+		}
+	}
+	return nil
+}
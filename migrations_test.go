@@ -0,0 +1,116 @@
+/*
+ * Minio Client (C) 2014, 2015 Minio, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path"
+
+	. "github.com/minio/check"
+	"github.com/minio/mc/pkg/quick"
+)
+
+// withTempMcConfigDir points mustGetMcConfigDir/getMcConfigDir at a fresh
+// temp HOME for the duration of the test so migration history snapshots
+// don't land in the real user's ~/.mc.
+func withTempMcConfigDir(c *C) (string, func()) {
+	root, err := ioutil.TempDir(os.TempDir(), "mc-home-")
+	c.Assert(err, IsNil)
+	oldHome := os.Getenv("HOME")
+	os.Setenv("HOME", root)
+	return root, func() {
+		os.Setenv("HOME", oldHome)
+		os.RemoveAll(root)
+	}
+}
+
+func (s *CmdTestSuite) TestMigrateConfigPreservesDefaultAliases(c *C) {
+	root, cleanup := withTempMcConfigDir(c)
+	defer cleanup()
+
+	configFile := path.Join(root, "config.json")
+	v1, err := quick.New(newConfigV1())
+	c.Assert(err, IsNil)
+	c.Assert(v1.Save(configFile), IsNil)
+
+	c.Assert(migrateConfig(configFile), IsNil)
+
+	version, err := configVersion(configFile)
+	c.Assert(err, IsNil)
+	c.Assert(version, Equals, "2.0.0")
+
+	v2, err := quick.New(newConfigV2())
+	c.Assert(err, IsNil)
+	c.Assert(v2.Load(configFile), IsNil)
+	data := v2.Data().(*configV2)
+
+	want := map[string]string{
+		"s3":        "https://s3.amazonaws.com",
+		"play":      "http://play.minio.io:9000",
+		"localhost": "http://localhost:9000",
+	}
+	for name, url := range want {
+		alias, ok := data.Aliases[name]
+		c.Assert(ok, Equals, true)
+		c.Assert(alias.URL, Equals, url)
+	}
+}
+
+func (s *CmdTestSuite) TestMigrateConfigSnapshotIsRestorable(c *C) {
+	root, cleanup := withTempMcConfigDir(c)
+	defer cleanup()
+
+	configFile := path.Join(root, "config.json")
+	v1, err := quick.New(newConfigV1())
+	c.Assert(err, IsNil)
+	c.Assert(v1.Save(configFile), IsNil)
+	preMigrationRaw, err := ioutil.ReadFile(configFile)
+	c.Assert(err, IsNil)
+
+	c.Assert(migrateConfig(configFile), IsNil)
+
+	entries, err := listConfigHistory()
+	c.Assert(err, IsNil)
+	c.Assert(len(entries) > 0, Equals, true)
+
+	c.Assert(restoreConfigHistory(configFile, entries[0].ID), IsNil)
+	restored, err := ioutil.ReadFile(configFile)
+	c.Assert(err, IsNil)
+	c.Assert(string(restored), Equals, string(preMigrationRaw))
+}
+
+func (s *CmdTestSuite) TestMigrateConfigRollsBackOnInvalidAlias(c *C) {
+	root, cleanup := withTempMcConfigDir(c)
+	defer cleanup()
+
+	configFile := path.Join(root, "config.json")
+	badConf := newConfigV1()
+	badConf.Aliases["0bad"] = "not a url but still parses" // invalid alias name
+	qc, err := quick.New(badConf)
+	c.Assert(err, IsNil)
+	c.Assert(qc.Save(configFile), IsNil)
+	before, err := ioutil.ReadFile(configFile)
+	c.Assert(err, IsNil)
+
+	err = migrateConfig(configFile)
+	c.Assert(err, Not(IsNil))
+
+	after, err := ioutil.ReadFile(configFile)
+	c.Assert(err, IsNil)
+	c.Assert(string(after), Equals, string(before))
+}
@@ -0,0 +1,153 @@
+/*
+ * Minio Client (C) 2014, 2015 Minio, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// policyConfig is the `policy` section of config.json: a default policy
+// endpoint plus optional per-alias overrides.
+type policyConfig struct {
+	URL       string
+	AuthToken string
+	FailOpen  bool
+	Aliases   map[string]policyConfig `json:",omitempty"`
+}
+
+// forAlias returns the effective policyConfig for alias, falling back to
+// the top-level URL and AuthToken where an override leaves them empty.
+// FailOpen is always the override's own value.
+func (pc policyConfig) forAlias(alias string) policyConfig {
+	override, ok := pc.Aliases[alias]
+	if !ok {
+		return pc
+	}
+	if override.URL == "" {
+		override.URL = pc.URL
+	}
+	if override.AuthToken == "" {
+		override.AuthToken = pc.AuthToken
+	}
+	return override
+}
+
+// errPolicyDenied is returned when the policy endpoint rejects an
+// operation. It is never worth retrying, so isValidRetry returns false
+// for it.
+type errPolicyDenied struct {
+	action   string
+	resource string
+}
+
+func (e *errPolicyDenied) Error() string {
+	return fmt.Sprintf("policy denied %s on %s", e.action, e.resource)
+}
+
+// policyInput is the JSON document posted to the policy endpoint ahead
+// of a mutating S3 operation, in the shape OPA's input document expects.
+type policyInput struct {
+	Input policyInputFields `json:"input"`
+}
+
+type policyInputFields struct {
+	User       string            `json:"user"`
+	Action     string            `json:"action"`
+	Resource   string            `json:"resource"`
+	Conditions map[string]string `json:"conditions,omitempty"`
+}
+
+// policyDecision is OPA's standard `{"result": ...}` envelope.
+type policyDecision struct {
+	Result struct {
+		Allow bool `json:"allow"`
+	} `json:"result"`
+}
+
+// policyAuthorizer posts a policyInput to cfg.URL before a mutating S3
+// call and aborts the operation unless the decision's result.allow is
+// true.
+type policyAuthorizer struct {
+	cfg    policyConfig
+	client *http.Client
+}
+
+func newPolicyAuthorizer(cfg policyConfig) *policyAuthorizer {
+	return &policyAuthorizer{cfg: cfg, client: &http.Client{Timeout: 10 * time.Second}}
+}
+
+// mutatingActions lists the S3 operations the policy hook gates: object
+// writes/deletes and bucket policy changes.
+var mutatingActions = map[string]bool{
+	"PutObject":       true,
+	"RemoveObject":    true,
+	"PutBucketPolicy": true,
+}
+
+// Authorize consults the policy endpoint for user performing action on
+// resource. If cfg.URL is unset the hook is disabled and every call is
+// allowed. A network or decode failure is treated according to
+// cfg.FailOpen.
+func (a *policyAuthorizer) Authorize(user, action, resource string, conditions map[string]string) error {
+	if a.cfg.URL == "" || !mutatingActions[action] {
+		return nil
+	}
+
+	body, err := json.Marshal(policyInput{Input: policyInputFields{
+		User:       user,
+		Action:     action,
+		Resource:   resource,
+		Conditions: conditions,
+	}})
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequest(http.MethodPost, a.cfg.URL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if a.cfg.AuthToken != "" {
+		req.Header.Set("Authorization", "Bearer "+a.cfg.AuthToken)
+	}
+
+	resp, err := a.client.Do(req)
+	if err != nil {
+		if a.cfg.FailOpen {
+			return nil
+		}
+		return err
+	}
+	defer resp.Body.Close()
+
+	var decision policyDecision
+	if err := json.NewDecoder(resp.Body).Decode(&decision); err != nil {
+		if a.cfg.FailOpen {
+			return nil
+		}
+		return err
+	}
+	if !decision.Result.Allow {
+		return &errPolicyDenied{action: action, resource: resource}
+	}
+	return nil
+}
@@ -0,0 +1,156 @@
+/*
+ * Minio Client (C) 2014, 2015 Minio, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path"
+
+	. "github.com/minio/check"
+	"github.com/minio/mc/pkg/quick"
+)
+
+func (s *CmdTestSuite) TestPolicyAuthorizerAllows(c *C) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"result":{"allow":true}}`)
+	}))
+	defer srv.Close()
+
+	a := newPolicyAuthorizer(policyConfig{URL: srv.URL})
+	err := a.Authorize("user1", "PutObject", "bucket/key", nil)
+	c.Assert(err, IsNil)
+}
+
+func (s *CmdTestSuite) TestPolicyAuthorizerDenies(c *C) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"result":{"allow":false}}`)
+	}))
+	defer srv.Close()
+
+	a := newPolicyAuthorizer(policyConfig{URL: srv.URL})
+	err := a.Authorize("user1", "RemoveObject", "bucket/key", nil)
+	c.Assert(err, FitsTypeOf, &errPolicyDenied{})
+	c.Assert(isValidRetry(err), Equals, false)
+}
+
+func (s *CmdTestSuite) TestPolicyAuthorizerSkipsNonMutatingActions(c *C) {
+	called := false
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		called = true
+		fmt.Fprint(w, `{"result":{"allow":false}}`)
+	}))
+	defer srv.Close()
+
+	a := newPolicyAuthorizer(policyConfig{URL: srv.URL})
+	err := a.Authorize("user1", "GetObject", "bucket/key", nil)
+	c.Assert(err, IsNil)
+	c.Assert(called, Equals, false)
+}
+
+func (s *CmdTestSuite) TestPolicyAuthorizerFailOpenOnError(c *C) {
+	a := newPolicyAuthorizer(policyConfig{URL: "http://127.0.0.1:0", FailOpen: true})
+	err := a.Authorize("user1", "PutObject", "bucket/key", nil)
+	c.Assert(err, IsNil)
+}
+
+func (s *CmdTestSuite) TestPolicyConfigForAlias(c *C) {
+	pc := policyConfig{
+		URL: "http://default",
+		Aliases: map[string]policyConfig{
+			"play": {URL: "http://play-override"},
+		},
+	}
+	c.Assert(pc.forAlias("play").URL, Equals, "http://play-override")
+	c.Assert(pc.forAlias("s3").URL, Equals, "http://default")
+}
+
+func (s *CmdTestSuite) TestS3ClientAppliesPerAliasPolicyOverride(c *C) {
+	defaultCalled := false
+	defaultSrv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		defaultCalled = true
+		fmt.Fprint(w, `{"result":{"allow":true}}`)
+	}))
+	defer defaultSrv.Close()
+
+	overrideCalled := false
+	overrideSrv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		overrideCalled = true
+		fmt.Fprint(w, `{"result":{"allow":false}}`)
+	}))
+	defer overrideSrv.Close()
+
+	old := globalPolicyConfig
+	defer func() { globalPolicyConfig = old }()
+	globalPolicyConfig = policyConfig{
+		URL: defaultSrv.URL,
+		Aliases: map[string]policyConfig{
+			"play": {URL: overrideSrv.URL},
+		},
+	}
+
+	cl, err := newS3ClientForAlias("play", "http://play.minio.io:9000", credValue{}, false)
+	c.Assert(err, IsNil)
+	err = cl.PutObject("bucket", "key")
+	c.Assert(err, FitsTypeOf, &errPolicyDenied{})
+	c.Assert(overrideCalled, Equals, true)
+	c.Assert(defaultCalled, Equals, false)
+}
+
+func (s *CmdTestSuite) TestLoadGlobalPolicyConfig(c *C) {
+	root, cleanup := withTempMcConfigDir(c)
+	defer cleanup()
+
+	old := globalPolicyConfig
+	defer func() { globalPolicyConfig = old }()
+	globalPolicyConfig = policyConfig{}
+
+	mcDir := path.Join(root, ".mc")
+	c.Assert(os.MkdirAll(mcDir, 0700), IsNil)
+
+	conf := newConfigV1()
+	conf.Policy = policyConfig{URL: "http://policy.example.com", FailOpen: true}
+	qc, err := quick.New(conf)
+	c.Assert(err, IsNil)
+	c.Assert(qc.Save(path.Join(mcDir, "config.json")), IsNil)
+
+	c.Assert(loadGlobalPolicyConfig(), IsNil)
+	c.Assert(globalPolicyConfig.URL, Equals, "http://policy.example.com")
+	c.Assert(globalPolicyConfig.FailOpen, Equals, true)
+}
+
+func (s *CmdTestSuite) TestMigrateConfigCarriesPolicyForward(c *C) {
+	root, cleanup := withTempMcConfigDir(c)
+	defer cleanup()
+
+	configFile := path.Join(root, "config.json")
+	v1 := newConfigV1()
+	v1.Policy = policyConfig{URL: "http://policy.example.com"}
+	qc, err := quick.New(v1)
+	c.Assert(err, IsNil)
+	c.Assert(qc.Save(configFile), IsNil)
+
+	c.Assert(migrateConfig(configFile), IsNil)
+
+	v2, err := quick.New(newConfigV2())
+	c.Assert(err, IsNil)
+	c.Assert(v2.Load(configFile), IsNil)
+	data := v2.Data().(*configV2)
+	c.Assert(data.Policy.URL, Equals, "http://policy.example.com")
+}
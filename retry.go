@@ -0,0 +1,189 @@
+/*
+ * Minio Client (C) 2014, 2015 Minio, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"net"
+	"net/http"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/minio/minio/pkg/iodine"
+)
+
+// globalDebugFlag, when true, turns on retryWithBackoff's per-attempt
+// logging to stderr. Nothing in this package sets it.
+var globalDebugFlag bool
+
+// isValidRetry returns true for the class of transient network errors mc
+// considers worth a retry: DNS failures and read/write/dial errors. It
+// unwraps iodine-wrapped errors first since most of mc's internal errors
+// pass through iodine.New.
+func isValidRetry(err error) bool {
+	if err == nil {
+		return false
+	}
+	err = unwrapIodine(err)
+	switch e := err.(type) {
+	case *net.DNSError:
+		return true
+	case *net.OpError:
+		switch e.Op {
+		case "read", "write", "dial":
+			return true
+		}
+		return false
+	default:
+		return false
+	}
+}
+
+// unwrapIodine returns the original error wrapped by iodine.New, so
+// callers that need to type-switch on the underlying error (net.DNSError,
+// *net.OpError, ...) don't have to know about iodine's own type.
+func unwrapIodine(err error) error {
+	if ie, ok := err.(*iodine.Error); ok {
+		return ie.Cause
+	}
+	return err
+}
+
+const (
+	retryBaseDelay   = 100 * time.Millisecond
+	retryCapDelay    = 30 * time.Second
+	retryMaxAttempts = 10
+)
+
+// httpStatusError lets retryWithBackoff recognize a rejected response's
+// status code without having to parse the transport error itself.
+type httpStatusError struct {
+	StatusCode int
+}
+
+func (e *httpStatusError) Error() string {
+	return fmt.Sprintf("http status %d", e.StatusCode)
+}
+
+// s3ErrorCode lets retryWithBackoff recognize a retriable S3 API error
+// code (e.g. "SlowDown") surfaced from a decoded S3 error response.
+type s3ErrorCode struct {
+	Code string
+}
+
+func (e *s3ErrorCode) Error() string { return "s3 error: " + e.Code }
+
+// isRetriableStatusOrCode reports whether err represents an HTTP 429/503
+// or an S3 SlowDown/RequestTimeout error code.
+func isRetriableStatusOrCode(err error) bool {
+	switch e := unwrapIodine(err).(type) {
+	case *httpStatusError:
+		return e.StatusCode == http.StatusTooManyRequests || e.StatusCode == http.StatusServiceUnavailable
+	case *s3ErrorCode:
+		return e.Code == "SlowDown" || e.Code == "RequestTimeout"
+	default:
+		return false
+	}
+}
+
+// isRetriableError is the union isValidRetry consults plus the HTTP
+// status/S3 error code cases retryWithBackoff additionally treats as
+// transient.
+func isRetriableError(err error) bool {
+	return isValidRetry(err) || isRetriableStatusOrCode(err)
+}
+
+// isAuthError reports whether err represents an HTTP 401/403 rejection,
+// the signal s3Client.call uses to invalidate its credentialsChain and
+// re-resolve credentials rather than simply retrying with the same
+// (rejected) ones.
+func isAuthError(err error) bool {
+	e, ok := unwrapIodine(err).(*httpStatusError)
+	return ok && (e.StatusCode == http.StatusUnauthorized || e.StatusCode == http.StatusForbidden)
+}
+
+// retryMaxAttemptsFromEnv returns MC_RETRY_MAX if set to a positive
+// integer, otherwise retryMaxAttempts.
+func retryMaxAttemptsFromEnv() int {
+	if v := os.Getenv("MC_RETRY_MAX"); v != "" {
+		if n, err := strconv.Atoi(v); err == nil && n > 0 {
+			return n
+		}
+	}
+	return retryMaxAttempts
+}
+
+// retryCapFromEnv returns MC_RETRY_CAP (a Go duration string, e.g.
+// "30s") if set to a positive duration, otherwise retryCapDelay.
+func retryCapFromEnv() time.Duration {
+	if v := os.Getenv("MC_RETRY_CAP"); v != "" {
+		if d, err := time.ParseDuration(v); err == nil && d > 0 {
+			return d
+		}
+	}
+	return retryCapDelay
+}
+
+// fullJitterBackoff returns a random delay in [0, min(base*2^(attempt-1), cap)),
+// the "full jitter" strategy from the AWS architecture blog.
+func fullJitterBackoff(attempt int, cap time.Duration) time.Duration {
+	exp := retryBaseDelay * time.Duration(uint64(1)<<uint(attempt-1))
+	if exp <= 0 || exp > cap {
+		exp = cap
+	}
+	return time.Duration(rand.Int63n(int64(exp)))
+}
+
+// retryWithBackoff runs op, retrying on errors isRetriableError accepts
+// using full-jitter exponential backoff (base 100ms, cap MC_RETRY_CAP,
+// default 30s, at most MC_RETRY_MAX attempts, default 10). ctx cancellation aborts
+// immediately, including mid-sleep between attempts.
+func retryWithBackoff(ctx context.Context, op func() error) error {
+	maxAttempts := retryMaxAttemptsFromEnv()
+	capDelay := retryCapFromEnv()
+
+	var lastErr error
+	for attempt := 1; attempt <= maxAttempts; attempt++ {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		lastErr = op()
+		if lastErr == nil {
+			return nil
+		}
+		if !isRetriableError(lastErr) {
+			return lastErr
+		}
+		if attempt == maxAttempts {
+			break
+		}
+
+		delay := fullJitterBackoff(attempt, capDelay)
+		if globalDebugFlag {
+			fmt.Fprintf(os.Stderr, "debug: attempt %d/%d failed (%v), retrying in %s\n", attempt, maxAttempts, lastErr, delay)
+		}
+		select {
+		case <-time.After(delay):
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+	return lastErr
+}
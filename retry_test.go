@@ -0,0 +1,139 @@
+/*
+ * Minio Client (C) 2014, 2015 Minio, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"os"
+	"time"
+
+	. "github.com/minio/check"
+	"github.com/minio/minio/pkg/iodine"
+)
+
+func (s *CmdTestSuite) TestIsRetriableErrorTable(c *C) {
+	opError := &net.OpError{Op: "read", Net: "net", Addr: &testAddr{}, Err: errors.New("op error")}
+
+	cases := []struct {
+		err      error
+		retry    bool
+		describe string
+	}{
+		{nil, false, "nil"},
+		{errors.New("boom"), false, "plain error"},
+		{&net.DNSError{}, true, "DNSError"},
+		{iodine.New(&net.DNSError{}, nil), true, "iodine-wrapped DNSError"},
+		{opError, true, "OpError read"},
+		{iodine.New(opError, nil), true, "iodine-wrapped OpError read"},
+		{&httpStatusError{StatusCode: http.StatusTooManyRequests}, true, "HTTP 429"},
+		{&httpStatusError{StatusCode: http.StatusServiceUnavailable}, true, "HTTP 503"},
+		{&httpStatusError{StatusCode: http.StatusBadRequest}, false, "HTTP 400"},
+		{&s3ErrorCode{Code: "SlowDown"}, true, "S3 SlowDown"},
+		{&s3ErrorCode{Code: "RequestTimeout"}, true, "S3 RequestTimeout"},
+		{&s3ErrorCode{Code: "NoSuchBucket"}, false, "S3 NoSuchBucket"},
+	}
+	for _, tc := range cases {
+		c.Check(isRetriableError(tc.err), Equals, tc.retry, Commentf("%s", tc.describe))
+	}
+}
+
+func (s *CmdTestSuite) TestIsAuthErrorTable(c *C) {
+	cases := []struct {
+		err      error
+		auth     bool
+		describe string
+	}{
+		{nil, false, "nil"},
+		{errors.New("boom"), false, "plain error"},
+		{&httpStatusError{StatusCode: http.StatusUnauthorized}, true, "HTTP 401"},
+		{&httpStatusError{StatusCode: http.StatusForbidden}, true, "HTTP 403"},
+		{&httpStatusError{StatusCode: http.StatusBadRequest}, false, "HTTP 400"},
+		{iodine.New(&httpStatusError{StatusCode: http.StatusUnauthorized}, nil), true, "iodine-wrapped HTTP 401"},
+	}
+	for _, tc := range cases {
+		c.Check(isAuthError(tc.err), Equals, tc.auth, Commentf("%s", tc.describe))
+	}
+}
+
+func (s *CmdTestSuite) TestRetryWithBackoffRetriesRetriableErrors(c *C) {
+	os.Setenv("MC_RETRY_CAP", "5ms")
+	defer os.Unsetenv("MC_RETRY_CAP")
+
+	attempts := 0
+	err := retryWithBackoff(context.Background(), func() error {
+		attempts++
+		if attempts < 4 {
+			return &net.DNSError{}
+		}
+		return nil
+	})
+	c.Assert(err, IsNil)
+	c.Assert(attempts, Equals, 4)
+}
+
+func (s *CmdTestSuite) TestRetryWithBackoffGivesUpOnNonRetriable(c *C) {
+	attempts := 0
+	wantErr := errors.New("permanent failure")
+	err := retryWithBackoff(context.Background(), func() error {
+		attempts++
+		return wantErr
+	})
+	c.Assert(err, Equals, wantErr)
+	c.Assert(attempts, Equals, 1)
+}
+
+func (s *CmdTestSuite) TestRetryWithBackoffRespectsMaxAttempts(c *C) {
+	os.Setenv("MC_RETRY_MAX", "3")
+	os.Setenv("MC_RETRY_CAP", "5ms")
+	defer os.Unsetenv("MC_RETRY_MAX")
+	defer os.Unsetenv("MC_RETRY_CAP")
+
+	attempts := 0
+	err := retryWithBackoff(context.Background(), func() error {
+		attempts++
+		return &net.DNSError{}
+	})
+	c.Assert(err, FitsTypeOf, &net.DNSError{})
+	c.Assert(attempts, Equals, 3)
+}
+
+func (s *CmdTestSuite) TestRetryWithBackoffAbortsOnContextCancel(c *C) {
+	ctx, cancel := context.WithCancel(context.Background())
+	attempts := 0
+	done := make(chan error, 1)
+	go func() {
+		done <- retryWithBackoff(ctx, func() error {
+			attempts++
+			return &net.DNSError{}
+		})
+	}()
+	// Let the first attempt happen, then cancel while it would otherwise
+	// keep sleeping and retrying.
+	time.Sleep(20 * time.Millisecond)
+	cancel()
+
+	select {
+	case err := <-done:
+		c.Assert(err, Equals, context.Canceled)
+	case <-time.After(2 * time.Second):
+		c.Fatal("retryWithBackoff did not abort on context cancellation")
+	}
+	c.Assert(attempts >= 1, Equals, true)
+}
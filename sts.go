@@ -0,0 +1,224 @@
+/*
+ * Minio Client (C) 2014, 2015 Minio, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os/exec"
+	"strings"
+	"sync"
+	"time"
+)
+
+// stsCredentials is the temporary credential triple AssumeRoleWithClient
+// Grants hands back, parsed straight out of the STS XML response.
+type stsCredentials struct {
+	AccessKeyID     string    `xml:"AccessKeyId"`
+	SecretAccessKey string    `xml:"SecretAccessKey"`
+	SessionToken    string    `xml:"SessionToken"`
+	Expiration      time.Time `xml:"Expiration"`
+}
+
+// assumeRoleWithClientGrantsResponse mirrors the XML shape returned by
+// the target endpoint's STS service.
+type assumeRoleWithClientGrantsResponse struct {
+	XMLName xml.Name `xml:"AssumeRoleWithClientGrantsResponse"`
+	Result  struct {
+		Credentials stsCredentials `xml:"Credentials"`
+	} `xml:"AssumeRoleWithClientGrantsResult"`
+}
+
+// fetchJWT resolves the bearer token an STS alias presents to the
+// target's AssumeRoleWithClientGrants endpoint. A Command-based source
+// execs the command and uses its trimmed stdout; a TokenURL source runs
+// the OAuth2 client-credentials exchange.
+func fetchJWT(src *jwtSource) (string, error) {
+	if src.Command != "" {
+		return runCommandForToken(src.Command)
+	}
+	if src.TokenURL != "" {
+		return fetchClientCredentialsToken(src.TokenURL, src.ClientID, src.ClientSecret)
+	}
+	return "", fmt.Errorf("sts: no JWT source configured")
+}
+
+// runCommandForToken execs a shell command and returns its trimmed
+// stdout as the JWT.
+func runCommandForToken(command string) (string, error) {
+	out, err := exec.Command("sh", "-c", command).Output()
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(string(out)), nil
+}
+
+// clientCredentialsTokenResponse is the JSON document an OAuth2 token
+// endpoint returns for a client-credentials grant.
+type clientCredentialsTokenResponse struct {
+	AccessToken string `json:"access_token"`
+}
+
+// fetchClientCredentialsToken runs the OAuth2 client-credentials grant
+// against tokenURL and returns the resulting access token as the JWT.
+func fetchClientCredentialsToken(tokenURL, clientID, clientSecret string) (string, error) {
+	form := url.Values{}
+	form.Set("grant_type", "client_credentials")
+	form.Set("client_id", clientID)
+	form.Set("client_secret", clientSecret)
+
+	resp, err := http.PostForm(tokenURL, form)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("sts: token endpoint returned status %s", resp.Status)
+	}
+	var token clientCredentialsTokenResponse
+	if err := json.NewDecoder(resp.Body).Decode(&token); err != nil {
+		return "", err
+	}
+	if token.AccessToken == "" {
+		return "", fmt.Errorf("sts: token endpoint response had no access_token")
+	}
+	return token.AccessToken, nil
+}
+
+// assumeRoleWithClientGrants exchanges jwt at endpoint's STS service for
+// temporary credentials, retrying transient failures through
+// retryWithBackoff so attempts are spaced out and each response body is
+// closed before the next attempt starts.
+func assumeRoleWithClientGrants(endpoint, jwt string) (*stsCredentials, error) {
+	values := url.Values{}
+	values.Set("Action", "AssumeRoleWithClientGrants")
+	values.Set("Token", jwt)
+	reqURL := endpoint + "?" + values.Encode()
+
+	var creds stsCredentials
+	err := retryWithBackoff(context.Background(), func() error {
+		resp, err := http.Get(reqURL)
+		if err != nil {
+			return err
+		}
+		defer resp.Body.Close()
+
+		if resp.StatusCode != http.StatusOK {
+			return fmt.Errorf("sts: AssumeRoleWithClientGrants failed with status %s", resp.Status)
+		}
+		var parsed assumeRoleWithClientGrantsResponse
+		if err := xml.NewDecoder(resp.Body).Decode(&parsed); err != nil {
+			return err
+		}
+		creds = parsed.Result.Credentials
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &creds, nil
+}
+
+// stsCredentialsCache caches temporary credentials per alias, refreshing
+// them automatically shortly before Expiration.
+type stsCredentialsCache struct {
+	mu    sync.Mutex
+	byKey map[string]*stsCredentials
+}
+
+var globalSTSCache = &stsCredentialsCache{byKey: map[string]*stsCredentials{}}
+
+// Get returns cached credentials for alias if still valid, otherwise
+// exchanges jwt at endpoint for a fresh set and caches it.
+func (cache *stsCredentialsCache) Get(alias, endpoint, jwt string) (*stsCredentials, error) {
+	cache.mu.Lock()
+	cached, ok := cache.byKey[alias]
+	cache.mu.Unlock()
+	if ok && time.Now().Add(time.Minute).Before(cached.Expiration) {
+		return cached, nil
+	}
+
+	creds, err := assumeRoleWithClientGrants(endpoint, jwt)
+	if err != nil {
+		return nil, err
+	}
+	cache.mu.Lock()
+	cache.byKey[alias] = creds
+	cache.mu.Unlock()
+	return creds, nil
+}
+
+// clientGrantsProvider is a credentialsProvider backed by an STS alias:
+// it exchanges the alias's configured JWT for temporary credentials and
+// reports IsExpired once those are about to lapse.
+type clientGrantsProvider struct {
+	alias    string
+	endpoint string
+	jwt      *jwtSource
+	cache    *stsCredentialsCache
+}
+
+func newClientGrantsProvider(alias, endpoint string, jwt *jwtSource) *clientGrantsProvider {
+	return &clientGrantsProvider{alias: alias, endpoint: endpoint, jwt: jwt, cache: globalSTSCache}
+}
+
+func (p *clientGrantsProvider) Retrieve() (credValue, error) {
+	token, err := fetchJWT(p.jwt)
+	if err != nil {
+		return credValue{}, err
+	}
+	creds, err := p.cache.Get(p.alias, p.endpoint, token)
+	if err != nil {
+		return credValue{}, err
+	}
+	return credValue{
+		AccessKeyID:     creds.AccessKeyID,
+		SecretAccessKey: creds.SecretAccessKey,
+		SessionToken:    creds.SessionToken,
+		ProviderName:    "ClientGrantsProvider",
+	}, nil
+}
+
+func (p *clientGrantsProvider) IsExpired() bool {
+	p.cache.mu.Lock()
+	defer p.cache.mu.Unlock()
+	cached, ok := p.cache.byKey[p.alias]
+	if !ok {
+		return true
+	}
+	return time.Now().Add(time.Minute).After(cached.Expiration)
+}
+
+// getNewClientV2 builds a client for an aliasV2 entry, choosing the
+// static hostConfig chain or the STS client-grants provider depending on
+// the alias's kind.
+func getNewClientV2(alias string, cfg aliasV2, debug bool) (client, error) {
+	if !cfg.isSTS() {
+		return getNewClientForAlias(alias, cfg.URL, cfg.Creds, debug)
+	}
+	provider := newClientGrantsProvider(alias, cfg.URL, cfg.JWT)
+	value, err := provider.Retrieve()
+	if err != nil {
+		return nil, err
+	}
+	return newS3ClientForAlias(alias, cfg.URL, value, debug)
+}
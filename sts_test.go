@@ -0,0 +1,111 @@
+/*
+ * Minio Client (C) 2014, 2015 Minio, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"time"
+
+	. "github.com/minio/check"
+)
+
+func stsXMLResponse(expiry time.Time) string {
+	return fmt.Sprintf(`<AssumeRoleWithClientGrantsResponse>
+  <AssumeRoleWithClientGrantsResult>
+    <Credentials>
+      <AccessKeyId>AKIDTEST</AccessKeyId>
+      <SecretAccessKey>secret</SecretAccessKey>
+      <SessionToken>token</SessionToken>
+      <Expiration>%s</Expiration>
+    </Credentials>
+  </AssumeRoleWithClientGrantsResult>
+</AssumeRoleWithClientGrantsResponse>`, expiry.Format(time.RFC3339))
+}
+
+func (s *CmdTestSuite) TestAssumeRoleWithClientGrants(c *C) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		c.Assert(r.URL.Query().Get("Action"), Equals, "AssumeRoleWithClientGrants")
+		c.Assert(r.URL.Query().Get("Token"), Equals, "the-jwt")
+		fmt.Fprint(w, stsXMLResponse(time.Now().Add(time.Hour)))
+	}))
+	defer srv.Close()
+
+	creds, err := assumeRoleWithClientGrants(srv.URL, "the-jwt")
+	c.Assert(err, IsNil)
+	c.Assert(creds.AccessKeyID, Equals, "AKIDTEST")
+	c.Assert(creds.SecretAccessKey, Equals, "secret")
+	c.Assert(creds.SessionToken, Equals, "token")
+}
+
+func (s *CmdTestSuite) TestFetchJWTClientCredentials(c *C) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		c.Assert(r.FormValue("grant_type"), Equals, "client_credentials")
+		c.Assert(r.FormValue("client_id"), Equals, "id1")
+		c.Assert(r.FormValue("client_secret"), Equals, "secret1")
+		fmt.Fprint(w, `{"access_token":"the-jwt"}`)
+	}))
+	defer srv.Close()
+
+	token, err := fetchJWT(&jwtSource{TokenURL: srv.URL, ClientID: "id1", ClientSecret: "secret1"})
+	c.Assert(err, IsNil)
+	c.Assert(token, Equals, "the-jwt")
+}
+
+func (s *CmdTestSuite) TestFetchJWTClientCredentialsDeniedByServer(c *C) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusUnauthorized)
+	}))
+	defer srv.Close()
+
+	_, err := fetchJWT(&jwtSource{TokenURL: srv.URL, ClientID: "id1", ClientSecret: "wrong"})
+	c.Assert(err, Not(IsNil))
+}
+
+func (s *CmdTestSuite) TestSTSCredentialsCacheAutoRefresh(c *C) {
+	calls := 0
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		expiry := time.Now().Add(-time.Minute)
+		if calls > 1 {
+			expiry = time.Now().Add(time.Hour)
+		}
+		fmt.Fprint(w, stsXMLResponse(expiry))
+	}))
+	defer srv.Close()
+
+	cache := &stsCredentialsCache{byKey: map[string]*stsCredentials{}}
+	first, err := cache.Get("alias1", srv.URL, "the-jwt")
+	c.Assert(err, IsNil)
+	c.Assert(first.AccessKeyID, Equals, "AKIDTEST")
+	c.Assert(calls, Equals, 1)
+
+	// First response is already expired, so a second Get must hit the
+	// server again rather than serving the stale cached entry.
+	second, err := cache.Get("alias1", srv.URL, "the-jwt")
+	c.Assert(err, IsNil)
+	c.Assert(second, Not(Equals), first)
+	c.Assert(calls, Equals, 2)
+
+	// Now cached credentials are valid for an hour, a third Get must not
+	// hit the server again.
+	third, err := cache.Get("alias1", srv.URL, "the-jwt")
+	c.Assert(err, IsNil)
+	c.Assert(third, Equals, second)
+	c.Assert(calls, Equals, 2)
+}